@@ -2,15 +2,120 @@ package choronolog
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrClosed is returned by Flush once the Logger has been closed, instead
+// of blocking forever waiting on
+// goroutines that Close has already torn down.
+var ErrClosed = errors.New("chronolog: logger is closed")
+
+// backupTimeFormat is used for backup file names instead of time.RFC3339
+// because RFC3339 contains colons, which are invalid in Windows filenames.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// dailyBackupTimeFormat and hourlyBackupTimeFormat name boundary-triggered
+// backups after the rotation period in which the rotation happens (the one
+// just entered), for Config.Daily/Config.Hourly.
+const (
+	dailyBackupTimeFormat  = "2006-01-02"
+	hourlyBackupTimeFormat = "2006-01-02T15"
+)
+
+// backupTimeFormats lists every format listBackups may encounter, tried in
+// order, so daily/hourly/size-triggered backups are all first-class.
+var backupTimeFormats = []string{backupTimeFormat, hourlyBackupTimeFormat, dailyBackupTimeFormat}
+
+func parseBackupTime(s string) (time.Time, error) {
+	var err error
+	for _, format := range backupTimeFormats {
+		var ts time.Time
+		if ts, err = time.Parse(format, s); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// Compression selects the codec used to compress rotated backups.
+type Compression string
+
+const (
+	CompressionNone Compression = ""     // backups are left uncompressed
+	CompressionGzip Compression = "gzip" // backups are compressed with gzip
+	CompressionZstd Compression = "zstd" // backups are compressed with zstd; requires a codec to be registered
+)
+
+// CodecMeta carries provenance about the backup being compressed so codecs
+// that support it (e.g. gzip) can embed the original name and mtime.
+type CodecMeta struct {
+	Name    string    // original (uncompressed) backup filename
+	ModTime time.Time // original file's modification time
+}
+
+// Codec compresses a rotated backup. Register custom codecs with
+// RegisterCodec to make them selectable via Config.Compression.
+type Codec interface {
+	Name() Compression // registry key, matches Config.Compression
+	Ext() string       // extension appended to the backup name, e.g. ".gz"
+	Compress(dst io.Writer, src io.Reader, meta CodecMeta) error
+}
+
+var codecs = map[Compression]Codec{}
+
+// RegisterCodec makes c available via Config.Compression.
+func RegisterCodec(c Codec) {
+	codecs[c.Name()] = c
+}
+
+func init() {
+	RegisterCodec(gzipCodec{})
+}
+
+// copyBufPool bounds memory use when streaming large backups through a codec.
+var copyBufPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 32*1024)
+		return &buf
+	},
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() Compression { return CompressionGzip }
+func (gzipCodec) Ext() string       { return ".gz" }
+
+func (gzipCodec) Compress(dst io.Writer, src io.Reader, meta CodecMeta) error {
+	gzWriter := gzip.NewWriter(dst)
+	gzWriter.Name = filepath.Base(meta.Name)
+	gzWriter.ModTime = meta.ModTime
+
+	buf := copyBufPool.Get().(*[]byte)
+	defer copyBufPool.Put(buf)
+
+	if _, err := io.CopyBuffer(gzWriter, src, *buf); err != nil {
+		gzWriter.Close()
+		return fmt.Errorf("failed to compress data: %w", err)
+	}
+
+	if err := gzWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finalize compressed file: %w", err)
+	}
+	return nil
+}
+
 type LogLevel int
 
 // defining logging levels
@@ -40,32 +145,83 @@ func (l LogLevel) String() string {
 	}
 }
 
-// log entry
-type LogEntry struct {
-	Timestamp string `json:"timestamp"`
-	Level     string `json:"level"`
-	Message   string `json:"message"`
+// Field is a structured key/value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F creates a Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
 }
 
+// DropPolicy controls what happens to a log entry when the async buffer is full.
+type DropPolicy int
+
+const (
+	DropPolicyBlock  DropPolicy = iota // block the caller until buffer space frees up
+	DropPolicyOldest                   // discard the oldest buffered entry to make room
+	DropPolicyNewest                   // discard the incoming entry
+)
+
 type Config struct { // logger configuration, represents the logger settings
 	FilePath              string        // path to log file
+	Fs                    Fs            // filesystem abstraction; defaults to the OS filesystem
 	MaxSize               int64         // maximum log size
 	MaxAge                time.Duration // maximum storage time for compressed logs
-	CompressOldLogs       bool          // do i need to compress old logs
+	MaxBackups            int           // maximum number of backup files to retain (0 = unlimited)
+	Compression           Compression   // codec used to compress rotated backups (default: CompressionNone)
 	JSONFormat            bool          // whether to use JSON format
 	TimestampFormat       string        // timestamp format
 	RotationCheckInterval time.Duration // rotation check interval
+	Daily                 bool          // rotate when the calendar day changes, in addition to MaxSize
+	Hourly                bool          // rotate when the calendar hour changes, in addition to MaxSize
+	Async                 bool          // buffer writes and hand them to a dedicated writer goroutine
+	BufferSize            int           // capacity of the async entry buffer
+	FlushInterval         time.Duration // how often the async writer flushes/syncs to disk
+	DropPolicy            DropPolicy    // what to do when the async buffer is full
+	EnableCaller          bool          // attach the caller's file:line to every entry
+	CallerSkip            int           // extra stack frames to skip before EnableCaller resolves the caller
+	Sampler               Sampler       // if set, consulted before formatting every entry; drops are counted and summarized on rotation
 }
 
-type Logger struct {
+// core holds the state shared by a Logger and every child Logger created via
+// With/WithContext: the open file, rotation bookkeeping and, in async mode,
+// the buffered writer goroutine.
+type core struct {
 	config      Config
-	file        *os.File
+	file        File
 	currentSize int64
 	mu          sync.Mutex
 	quitChan    chan struct{}
+
+	entries  chan []byte // buffered pre-formatted entries, only set when Async is enabled
+	flushReq chan chan struct{}
+	writerWg sync.WaitGroup
+
+	period time.Time // start of the current rotation period, set when Daily or Hourly is enabled
+
+	drops [LevelFatal + 1]int64 // entries dropped by config.Sampler since the last rotation, indexed by LogLevel
+
+	compressMu  sync.Mutex
+	compressing map[string]struct{} // backup paths currently being read by a compression goroutine; cleanupOldLogs must not remove these out from under it
+}
+
+// Logger writes leveled and raw log entries to a rotating file. It implements
+// io.Writer so it can be plugged underneath log.Logger, slog, zap, zerolog, etc.
+// Child loggers created via With/WithContext share the same underlying sink.
+type Logger struct {
+	c      *core
+	fields []Field
 }
 
+var _ io.Writer = (*Logger)(nil)
+
 func New(config Config) (*Logger, error) {
+	if config.Fs == nil {
+		config.Fs = osFs{}
+	}
 	if config.MaxSize == 0 {
 		config.MaxSize = 50 * 1024 * 1024 // default size = 50MB
 	}
@@ -78,26 +234,46 @@ func New(config Config) (*Logger, error) {
 	if config.RotationCheckInterval == 0 {
 		config.RotationCheckInterval = time.Minute // check rotation every minute
 	}
+	if config.Async && config.BufferSize == 0 {
+		config.BufferSize = 1024 // default async buffer capacity
+	}
+	if config.Async && config.FlushInterval == 0 {
+		config.FlushInterval = time.Second // default async flush interval
+	}
+
+	if config.Compression != CompressionNone {
+		if _, ok := codecs[config.Compression]; !ok {
+			return nil, fmt.Errorf("no codec registered for compression %q", config.Compression)
+		}
+	}
 
-	l := &Logger{
-		config:   config,
-		quitChan: make(chan struct{}),
+	c := &core{
+		config:      config,
+		quitChan:    make(chan struct{}),
+		compressing: make(map[string]struct{}),
 	}
 
-	if err := l.openFile(); err != nil {
+	if err := c.openFile(); err != nil {
 		return nil, err
 	}
 
-	go l.rotationChecker()
+	if config.Async {
+		c.entries = make(chan []byte, config.BufferSize)
+		c.flushReq = make(chan chan struct{})
+		c.writerWg.Add(1)
+		go c.writerLoop()
+	}
+
+	go c.rotationChecker()
 
-	return l, nil
+	return &Logger{c: c}, nil
 }
 
-func (l *Logger) openFile() error { // creating | opening a log file
-	if err := os.MkdirAll(filepath.Dir(l.config.FilePath), 0755); err != nil {
+func (c *core) openFile() error { // creating | opening a log file
+	if err := c.config.Fs.MkdirAll(filepath.Dir(c.config.FilePath), 0755); err != nil {
 		return fmt.Errorf("failed to create log directory: %w", err)
 	}
-	file, err := os.OpenFile(l.config.FilePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	file, err := c.config.Fs.OpenFile(c.config.FilePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create log directory: %w", err)
 	}
@@ -107,152 +283,630 @@ func (l *Logger) openFile() error { // creating | opening a log file
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	l.file = file
-	l.currentSize = info.Size()
+	c.file = file
+	c.currentSize = info.Size()
+	if c.config.Daily || c.config.Hourly {
+		c.period = c.currentPeriod()
+	}
 
 	return nil
 }
 
-func (l *Logger) rotationChecker() { // checking the need for log rotation
-	ticker := time.NewTicker(l.config.RotationCheckInterval)
+// currentPeriod returns the start of the rotation period now() falls into,
+// per Config.Daily/Config.Hourly.
+func (c *core) currentPeriod() time.Time {
+	now := time.Now()
+	if c.config.Hourly {
+		return now.Truncate(time.Hour)
+	}
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// needsTimeRotation reports whether the calendar boundary configured via
+// Daily/Hourly has been crossed since the file was last opened.
+func (c *core) needsTimeRotation() bool {
+	if !c.config.Daily && !c.config.Hourly {
+		return false
+	}
+	return c.currentPeriod().After(c.period)
+}
+
+func (c *core) rotationChecker() { // checking the need for log rotation
+	ticker := time.NewTicker(c.config.RotationCheckInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			l.mu.Lock()
-			if l.currentSize >= l.config.MaxSize {
-				if err := l.rotate(); err != nil {
+			c.mu.Lock()
+			if c.currentSize >= c.config.MaxSize || c.needsTimeRotation() {
+				if err := c.rotateLocked(); err != nil {
 					fmt.Fprintf(os.Stderr, "failed to rotate log file: %v\n", err)
 				}
 			}
-			l.mu.Unlock()
-		case <-l.quitChan:
+			c.mu.Unlock()
+		case <-c.quitChan:
 			return
 		}
 	}
 }
 
-func (l *Logger) rotate() error { // performing log rotation
-	if err := l.file.Close(); err != nil {
+// Rotate forces an immediate log rotation, e.g. in response to SIGHUP.
+func (l *Logger) Rotate() error {
+	l.c.mu.Lock()
+	defer l.c.mu.Unlock()
+	return l.c.rotateLocked()
+}
+
+// backupName returns a lumberjack-compatible backup path for the log file,
+// e.g. "app-2024-01-15T10-30-00.000.log". When timeTriggered is true (the
+// rotation was actually caused by crossing a Daily/Hourly boundary), the
+// name instead encodes the rotation period (e.g. "app-2024-01-15.log") so it
+// lines up with log-shipping windows. Size-triggered rotations always get
+// the full timestamp, even inside a Daily/Hourly period, so they don't
+// collide with (and silently clobber) that period's boundary backup.
+func (c *core) backupName(timeTriggered bool) string {
+	dir := filepath.Dir(c.config.FilePath)
+	base := filepath.Base(c.config.FilePath)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext)
+
+	format := backupTimeFormat
+	if timeTriggered {
+		switch {
+		case c.config.Hourly:
+			format = hourlyBackupTimeFormat
+		case c.config.Daily:
+			format = dailyBackupTimeFormat
+		}
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, time.Now().Format(format), ext))
+}
+
+// rotateLocked performs log rotation. c.mu must be held.
+func (c *core) rotateLocked() error {
+	// Captured before openFile (below) advances c.period, so it still
+	// reflects whatever caused this call: a crossed Daily/Hourly boundary,
+	// or a size-triggered/forced rotation happening to fall in the same period.
+	timeTriggered := c.needsTimeRotation()
+
+	if err := c.file.Close(); err != nil {
 		return fmt.Errorf("failed to close log file: %w", err)
 	}
 
-	backupName := l.config.FilePath + "." + time.Now().Format(time.RFC3339) // archive file
-	if err := os.Rename(l.config.FilePath, backupName); err != nil {
+	backupName := c.backupName(timeTriggered)
+	if err := c.config.Fs.Rename(c.config.FilePath, backupName); err != nil {
 		return fmt.Errorf("failed to rename log file: %w", err)
 	}
 
-	if l.config.CompressOldLogs {
+	if c.config.Compression != CompressionNone {
+		codec := codecs[c.config.Compression]
+
+		c.compressMu.Lock()
+		c.compressing[backupName] = struct{}{}
+		c.compressMu.Unlock()
+
 		go func() {
-			if err := l.compressFile(backupName, backupName+".gz"); err != nil {
+			defer func() {
+				c.compressMu.Lock()
+				delete(c.compressing, backupName)
+				c.compressMu.Unlock()
+			}()
+
+			compressedName := backupName + codec.Ext()
+			if err := c.compressFile(codec, backupName, compressedName); err != nil {
 				fmt.Fprintf(os.Stderr, "failed to compress log file: %v\n", err)
 				return
 			}
-			if err := os.Remove(backupName); err != nil {
+			if err := c.config.Fs.Remove(backupName); err != nil {
 				fmt.Fprintf(os.Stderr, "failed to remove old log file: %v\n", err)
 			}
 		}()
 	}
 
-	if err := l.openFile(); err != nil {
+	if err := c.openFile(); err != nil {
 		return fmt.Errorf("failed to create new log file: %w", err)
 	}
 
-	go l.cleanupOldLogs()
+	c.emitDropSummary()
+
+	go c.cleanupOldLogs()
 
 	return nil
 }
 
-func (l *Logger) compressFile(src, dst string) error { // compressing too large logs
-	srcFile, err := os.Open(src)
+// emitDropSummary writes a synthetic WARNING entry summarizing how many
+// entries config.Sampler has dropped per level since the last rotation, then
+// resets the counters. c.mu must be held; called from rotateLocked once the
+// new file is open, so the summary lands at the top of the fresh file.
+func (c *core) emitDropSummary() {
+	if c.config.Sampler == nil {
+		return
+	}
+
+	var parts []string
+	var total int64
+	for lvl := range c.drops {
+		n := atomic.SwapInt64(&c.drops[lvl], 0)
+		if n == 0 {
+			continue
+		}
+		total += n
+		parts = append(parts, fmt.Sprintf("%s=%d", LogLevel(lvl), n))
+	}
+	if total == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("sampler dropped %d entries since last rotation (%s)", total, strings.Join(parts, ", "))
+	line, err := c.formatSyntheticEntry(LevelWarning, message)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to format drop summary: %v\n", err)
+		return
+	}
+
+	n, err := c.file.Write(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write drop summary: %v\n", err)
+		return
+	}
+	c.currentSize += int64(n)
+}
+
+// formatSyntheticEntry renders a core-generated entry (e.g. the drop
+// summary) using the same timestamp/JSON conventions as Logger.formatEntry,
+// but without any child-logger fields or caller info to attach.
+func (c *core) formatSyntheticEntry(level LogLevel, message string) ([]byte, error) {
+	timestamp := time.Now().Format(c.config.TimestampFormat)
+
+	if c.config.JSONFormat {
+		obj := map[string]interface{}{
+			"timestamp": timestamp,
+			"level":     level.String(),
+			"message":   message,
+		}
+		jsonData, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal log entry: %w", err)
+		}
+		return append(jsonData, '\n'), nil
+	}
+
+	return []byte(fmt.Sprintf("%s - [%s]: %s\n", timestamp, level.String(), message)), nil
+}
+
+func (c *core) compressFile(codec Codec, src, dst string) error { // compressing too large logs
+	srcFile, err := c.config.Fs.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer srcFile.Close()
 
-	dstFile, err := os.Create(dst)
+	info, err := srcFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	dstFile, err := c.config.Fs.Create(dst)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer dstFile.Close()
 
-	gzWriter := gzip.NewWriter(dstFile)
-	defer gzWriter.Close()
+	meta := CodecMeta{Name: filepath.Base(src), ModTime: info.ModTime()}
+	if err := codec.Compress(dstFile, srcFile, meta); err != nil {
+		return err
+	}
 
-	if _, err := io.Copy(gzWriter, srcFile); err != nil {
-		return fmt.Errorf("failed to compress data %w", err)
+	// Only the source is removed by the caller, and only once the destination
+	// is durably on disk.
+	if err := dstFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync compressed file: %w", err)
 	}
 
 	return nil
 }
 
-func (l *Logger) cleanupOldLogs() { // self-cleaning of old logs
-	files, err := filepath.Glob(l.config.FilePath + ".*.gz")
+// backupFile is a rotated (optionally compressed) log file, together with
+// the timestamp parsed out of its name.
+type backupFile struct {
+	path string
+	ts   time.Time
+}
+
+// listBackups returns every backup of the log file, both compressed and
+// uncompressed, newest first.
+func (c *core) listBackups() ([]backupFile, error) {
+	base := filepath.Base(c.config.FilePath)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext)
+
+	matches, err := c.config.Fs.Glob(filepath.Join(filepath.Dir(c.config.FilePath), prefix+"-*"+ext+"*"))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to get log files: %v\n", err)
-		return
+		return nil, err
 	}
 
-	cutoffTime := time.Now().Add(-l.config.MaxAge)
+	var backups []backupFile
+	for _, m := range matches {
+		name := filepath.Base(m)
+		for _, codec := range codecs {
+			name = strings.TrimSuffix(name, codec.Ext())
+		}
+		name = strings.TrimSuffix(name, ext)
+		name = strings.TrimPrefix(name, prefix+"-")
 
-	for _, file := range files {
-		info, err := os.Stat(file)
+		ts, err := parseBackupTime(name)
 		if err != nil {
-			continue
+			continue // not one of our backups
 		}
+		backups = append(backups, backupFile{path: m, ts: ts})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ts.After(backups[j].ts)
+	})
+
+	return backups, nil
+}
 
-		if info.ModTime().Before(cutoffTime) {
-			if err := os.Remove(file); err != nil {
+func (c *core) cleanupOldLogs() { // self-cleaning of old logs
+	backups, err := c.listBackups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get log files: %v\n", err)
+		return
+	}
+
+	cutoffTime := time.Now().Add(-c.config.MaxAge)
+
+	for i, b := range backups {
+		expiredByAge := c.config.MaxAge > 0 && b.ts.Before(cutoffTime)
+		expiredByCount := c.config.MaxBackups > 0 && i >= c.config.MaxBackups
+
+		if expiredByAge || expiredByCount {
+			c.compressMu.Lock()
+			_, compressing := c.compressing[b.path]
+			c.compressMu.Unlock()
+			if compressing {
+				// Still being read by a compression goroutine; it'll be
+				// superseded by its compressed copy and swept up next rotation.
+				continue
+			}
+
+			if err := c.config.Fs.Remove(b.path); err != nil {
 				fmt.Fprintf(os.Stderr, "failed to remove old log file: %v\n", err)
 			}
 		}
 	}
 }
 
-func (l *Logger) write(level LogLevel, message string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// Write implements io.Writer, writing p to the log file verbatim (no level
+// formatting). In async mode the write is buffered and handed off to the
+// writer goroutine according to Config.DropPolicy; otherwise it happens
+// synchronously under the sink's lock.
+func (l *Logger) Write(p []byte) (int, error) {
+	return l.c.Write(p)
+}
 
-	entry := LogEntry{
-		Timestamp: time.Now().Format(time.RFC3339),
-		Level:     level.String(),
-		Message:   message,
+func (c *core) Write(p []byte) (int, error) {
+	if c.config.Async {
+		buf := make([]byte, len(p))
+		copy(buf, p)
+		c.enqueue(buf)
+		return len(p), nil
 	}
 
-	var logLine string
-	if l.config.JSONFormat {
-		jsonData, err := json.Marshal(entry)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to marshal log entry: %v\n", err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeLocked(p)
+}
+
+// writeLocked rotates the log file if p would push it past MaxSize or a
+// Daily/Hourly boundary has been crossed, then writes p to the underlying
+// file. c.mu must be held.
+func (c *core) writeLocked(p []byte) (int, error) {
+	needsRotation := c.needsTimeRotation() || (c.config.MaxSize > 0 && c.currentSize+int64(len(p)) > c.config.MaxSize)
+	if needsRotation {
+		if err := c.rotateLocked(); err != nil {
+			return 0, fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+
+	n, err := c.file.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to log file: %w", err)
+	}
+	c.currentSize += int64(n)
+	return n, nil
+}
+
+func (c *core) enqueue(p []byte) {
+	switch c.config.DropPolicy {
+	case DropPolicyNewest:
+		select {
+		case c.entries <- p:
+		default: // buffer full, drop the incoming entry
+		}
+	case DropPolicyOldest:
+		select {
+		case c.entries <- p:
+		default:
+			select {
+			case <-c.entries: // make room by dropping the oldest entry
+			default:
+			}
+			select {
+			case c.entries <- p:
+			default:
+			}
+		}
+	default: // DropPolicyBlock
+		select {
+		case c.entries <- p:
+		case <-c.quitChan: // closed: writerLoop is gone, drop rather than block forever
+		}
+	}
+}
+
+// writerLoop drains the async entry buffer and periodically syncs the file
+// to disk. It runs for the lifetime of the core when Config.Async is set.
+func (c *core) writerLoop() {
+	defer c.writerWg.Done()
+
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case p := <-c.entries:
+			c.writeBuffered(p)
+		case done := <-c.flushReq:
+			c.drainEntries()
+			_ = c.Sync()
+			close(done)
+		case <-ticker.C:
+			_ = c.Sync()
+		case <-c.quitChan:
+			c.drainEntries()
 			return
 		}
-		logLine = string(jsonData) + "\n"
-	} else {
-		logLine = fmt.Sprintf("%s - [%s]: %s", entry.Timestamp, level.String(), message)
-		logLine += "\n"
 	}
+}
+
+func (c *core) drainEntries() {
+	for {
+		select {
+		case p := <-c.entries:
+			c.writeBuffered(p)
+		default:
+			return
+		}
+	}
+}
 
-	n, err := fmt.Fprintf(l.file, "%s", logLine)
+func (c *core) writeBuffered(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.writeLocked(p); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
+
+// defaultCallerSkip accounts for the frames between callerInfo and the
+// public logging method the caller actually invoked (Debug/Info/.../Log).
+const defaultCallerSkip = 4
+
+func callerInfo(skip int) string {
+	_, file, line, ok := runtime.Caller(defaultCallerSkip + skip)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+}
+
+// formatEntry renders a single log entry, merging l.fields (from With and
+// WithContext) with any fields passed to this call. In JSON mode the fields
+// are flattened into the top-level object alongside timestamp/level/message;
+// in text mode they're appended as "key=value" pairs.
+func (l *Logger) formatEntry(level LogLevel, message string, fields []Field) ([]byte, error) {
+	timestamp := time.Now().Format(l.c.config.TimestampFormat)
+
+	var caller string
+	if l.c.config.EnableCaller {
+		caller = callerInfo(l.c.config.CallerSkip)
+	}
+
+	if l.c.config.JSONFormat {
+		obj := make(map[string]interface{}, 4+len(l.fields)+len(fields))
+		obj["timestamp"] = timestamp
+		obj["level"] = level.String()
+		obj["message"] = message
+		for _, f := range l.fields {
+			obj[f.Key] = f.Value
+		}
+		for _, f := range fields {
+			obj[f.Key] = f.Value
+		}
+		if caller != "" {
+			obj["caller"] = caller
+		}
+
+		jsonData, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal log entry: %w", err)
+		}
+		return append(jsonData, '\n'), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - [%s]: %s", timestamp, level.String(), message)
+	for _, f := range l.fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	if caller != "" {
+		fmt.Fprintf(&b, " caller=%s", caller)
+	}
+	b.WriteByte('\n')
+
+	return []byte(b.String()), nil
+}
+
+func (l *Logger) write(level LogLevel, message string, fields ...Field) {
+	if l.c.config.Sampler != nil && !l.c.config.Sampler.Sample(level) {
+		atomic.AddInt64(&l.c.drops[level], 1)
+		return
+	}
+
+	logLine, err := l.formatEntry(level, message, fields)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to write to log file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		return
 	}
 
-	l.currentSize += int64(n)
+	if _, err := l.c.Write(logLine); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write log entry: %v\n", err)
+	}
+}
+
+// Log writes a structured entry at level, merging fields on top of any set
+// via With/WithContext.
+func (l *Logger) Log(level LogLevel, msg string, fields ...Field) {
+	l.write(level, msg, fields...)
+}
+
+// With returns a child Logger that prepends fields to every entry it writes,
+// on top of any fields already set on l. The child shares the same
+// underlying sink (file, rotation state, async buffer) as l.
+func (l *Logger) With(fields ...Field) *Logger {
+	merged := make([]Field, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	merged = append(merged, fields...)
+	return &Logger{c: l.c, fields: merged}
+}
+
+type traceContextKey int
+
+const (
+	traceIDKey traceContextKey = iota
+	spanIDKey
+)
+
+// ContextWithTraceID returns a context carrying a trace ID that WithContext
+// will attach to every entry logged through the returned Logger.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// ContextWithSpanID returns a context carrying a span ID that WithContext
+// will attach to every entry logged through the returned Logger.
+func ContextWithSpanID(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, spanIDKey, spanID)
+}
+
+// WithContext returns a child Logger with the trace/span IDs carried by ctx
+// (if any) attached as fields to every entry.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	var fields []Field
+	if traceID, ok := ctx.Value(traceIDKey).(string); ok {
+		fields = append(fields, F("trace_id", traceID))
+	}
+	if spanID, ok := ctx.Value(spanIDKey).(string); ok {
+		fields = append(fields, F("span_id", spanID))
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+// Sync commits the current contents of the log file to stable storage.
+func (l *Logger) Sync() error {
+	return l.c.Sync()
 }
 
+func (c *core) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log file: %w", err)
+	}
+	return nil
+}
+
+// Flush blocks until all entries buffered by async mode have been written
+// and synced to disk. It is equivalent to Sync when async mode is disabled.
+func (l *Logger) Flush() error {
+	return l.c.Flush()
+}
+
+func (c *core) Flush() error {
+	if !c.config.Async {
+		return c.Sync()
+	}
+
+	done := make(chan struct{})
+	select {
+	case c.flushReq <- done:
+	case <-c.quitChan:
+		return ErrClosed
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-c.quitChan:
+		return ErrClosed
+	}
+}
+
+// Close stops the logger's background goroutines and closes the log file.
+// It affects every Logger sharing this sink (i.e. l and every Logger derived
+// from it via With/WithContext).
 func (l *Logger) Close() error {
-	close(l.quitChan)
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	return l.c.Close()
+}
+
+func (c *core) Close() error {
+	close(c.quitChan)
+	if c.config.Async {
+		c.writerWg.Wait()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if l.file != nil {
-		return l.file.Close()
+	if c.file != nil {
+		return c.file.Close()
 	}
 	return nil
 }
 
+// Stats reports how many entries Config.Sampler has dropped per level since
+// the last rotation. Counts reset to zero whenever a rotation emits the
+// synthetic drop-summary WARNING entry.
+type Stats struct {
+	Dropped [LevelFatal + 1]int64 // indexed by LogLevel
+}
+
+// Stats returns the current drop counters. It is safe to call concurrently
+// with logging.
+func (l *Logger) Stats() Stats {
+	var s Stats
+	for lvl := range s.Dropped {
+		s.Dropped[lvl] = atomic.LoadInt64(&l.c.drops[lvl])
+	}
+	return s
+}
+
 func (l *Logger) Debug(msg string) {
 	l.write(LevelDebug, msg)
 }
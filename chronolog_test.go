@@ -1,8 +1,16 @@
 package choronolog
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLogger(t *testing.T) {
@@ -15,9 +23,8 @@ func TestLogger(t *testing.T) {
 	defer os.Remove(tmpFile.Name())
 
 	config := Config{
-		FilePath:        tmpFile.Name(),
-		MaxSize:         1024,
-		CompressOldLogs: false,
+		FilePath: tmpFile.Name(),
+		MaxSize:  1024,
 	}
 
 	log, err := New(config)
@@ -44,3 +51,366 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestLoggerWrite(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	log, err := New(Config{FilePath: tmpFile.Name(), MaxSize: 1024})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	var _ io.Writer = log
+
+	n, err := log.Write([]byte("raw line\n"))
+	if err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if n != len("raw line\n") {
+		t.Fatalf("Write() n = %d, want %d", n, len("raw line\n"))
+	}
+}
+
+func TestLoggerAsyncWrite(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	log, err := New(Config{
+		FilePath:      tmpFile.Name(),
+		MaxSize:       1024,
+		Async:         true,
+		BufferSize:    4,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if _, err := log.Write([]byte("async line\n")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	data, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "async line\n" {
+		t.Fatalf("file contents = %q, want %q", data, "async line\n")
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+}
+
+func TestLoggerFlushAfterCloseReturnsErrClosed(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	log, err := New(Config{
+		FilePath:   tmpFile.Name(),
+		MaxSize:    1024,
+		Async:      true,
+		BufferSize: 4,
+	})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- log.Flush() }()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("Flush() after Close() error = %v, want ErrClosed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Flush() after Close() blocked instead of returning ErrClosed")
+	}
+}
+
+func TestLoggerRotateEnforcesMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	log, err := New(Config{FilePath: logPath, MaxSize: 1024, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 4; i++ {
+		if err := log.Rotate(); err != nil {
+			t.Fatalf("Rotate() error: %v", err)
+		}
+	}
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // cleanupOldLogs runs asynchronously after rotation
+
+	backups, err := log.c.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() error: %v", err)
+	}
+	if len(backups) > 2 {
+		t.Fatalf("expected at most 2 backups, got %d: %v", len(backups), backups)
+	}
+}
+
+func TestCleanupSkipsBackupBeingCompressed(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	log, err := New(Config{FilePath: logPath, MaxSize: 1024, MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	// Fabricate the backups directly rather than via Rotate(), which also
+	// kicks off its own async cleanupOldLogs and would race with this test.
+	now := time.Now()
+	older := filepath.Join(dir, "app-"+now.Add(-time.Minute).Format(backupTimeFormat)+".log")
+	newer := filepath.Join(dir, "app-"+now.Format(backupTimeFormat)+".log")
+	if err := os.WriteFile(older, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newer, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log.c.compressMu.Lock()
+	log.c.compressing[older] = struct{}{}
+	log.c.compressMu.Unlock()
+
+	log.c.cleanupOldLogs() // MaxBackups: 1 would otherwise expire "older"
+
+	if _, err := os.Stat(older); err != nil {
+		t.Fatalf("backup still being compressed was removed by cleanup: %v", err)
+	}
+
+	log.c.compressMu.Lock()
+	delete(log.c.compressing, older)
+	log.c.compressMu.Unlock()
+
+	log.c.cleanupOldLogs()
+	if _, err := os.Stat(older); !os.IsNotExist(err) {
+		t.Fatalf("expected backup to be removed once no longer compressing, Stat() error = %v", err)
+	}
+}
+
+func TestLoggerCompressionDefaultsMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	log, err := New(Config{FilePath: logPath, Compression: CompressionGzip})
+	if err != nil {
+		t.Fatalf("New() error: %v, want compression to use the default MaxSize", err)
+	}
+	defer log.Close()
+}
+
+func TestLoggerCompressesBackupsWithGzip(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	log, err := New(Config{FilePath: logPath, MaxSize: 1024, Compression: CompressionGzip})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	if err := log.Rotate(); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // compression happens asynchronously after rotation
+
+	backups, err := log.c.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() error: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got %d: %v", len(backups), backups)
+	}
+	if filepath.Ext(backups[0].path) != ".gz" {
+		t.Fatalf("expected backup to be gzipped, got %q", backups[0].path)
+	}
+
+	f, err := os.Open(backups[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error: %v", err)
+	}
+
+	wantName := strings.TrimSuffix(filepath.Base(backups[0].path), ".gz")
+	if gzr.Name != wantName {
+		t.Fatalf("gzip header Name = %q, want %q", gzr.Name, wantName)
+	}
+}
+
+func TestLoggerNeedsTimeRotation(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	log, err := New(Config{FilePath: logPath, MaxSize: 1024, Daily: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	if log.c.needsTimeRotation() {
+		t.Fatal("needsTimeRotation() = true immediately after opening the file")
+	}
+
+	log.c.period = log.c.period.AddDate(0, 0, -1) // simulate having opened the file yesterday
+	if !log.c.needsTimeRotation() {
+		t.Fatal("needsTimeRotation() = false after crossing the daily boundary")
+	}
+
+	if err := log.Rotate(); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+
+	backups, err := log.c.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() error: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got %d: %v", len(backups), backups)
+	}
+
+	wantName := "app-" + time.Now().Format(dailyBackupTimeFormat) + ".log"
+	if got := filepath.Base(backups[0].path); got != wantName {
+		t.Fatalf("backup name = %q, want %q", got, wantName)
+	}
+}
+
+func TestLoggerSizeRotationsWithinPeriodDontClobberEachOther(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	log, err := New(Config{FilePath: logPath, MaxSize: 10, Daily: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := log.Write([]byte("more than 10 bytes\n")); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond) // distinct backupTimeFormat (millisecond precision) per rotation
+	}
+
+	backups, err := log.c.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() error: %v", err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("expected 3 distinct backups from 3 size-triggered rotations in the same day, got %d: %v", len(backups), backups)
+	}
+}
+
+func TestLoggerStructuredFields(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	log, err := New(Config{FilePath: tmpFile.Name(), MaxSize: 1024, JSONFormat: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	child := log.With(F("service", "api")).WithContext(ContextWithTraceID(context.Background(), "trace-123"))
+	child.Log(LevelInfo, "request handled", F("status", float64(200)))
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	data, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("json.Unmarshal() error: %v, data: %s", err, data)
+	}
+
+	if entry["message"] != "request handled" {
+		t.Fatalf("message = %v, want %q", entry["message"], "request handled")
+	}
+	if entry["service"] != "api" {
+		t.Fatalf("service = %v, want %q", entry["service"], "api")
+	}
+	if entry["trace_id"] != "trace-123" {
+		t.Fatalf("trace_id = %v, want %q", entry["trace_id"], "trace-123")
+	}
+	if entry["status"] != float64(200) {
+		t.Fatalf("status = %v, want %v", entry["status"], 200)
+	}
+}
+
+func TestLoggerEnableCaller(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	log, err := New(Config{FilePath: tmpFile.Name(), MaxSize: 1024, EnableCaller: true})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	log.Info("hello")
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	data, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "caller=chronolog_test.go:") {
+		t.Fatalf("expected caller annotation, got %q", data)
+	}
+}
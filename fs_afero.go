@@ -0,0 +1,41 @@
+//go:build afero
+
+package choronolog
+
+import (
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// AferoFs adapts an afero.Fs (https://github.com/spf13/afero) to this
+// package's Fs interface, so rotated backups can be archived anywhere afero
+// has a backend for: afero.NewMemMapFs() for tests, or a remote-backed
+// afero.Fs (e.g. S3) for off-box archival.
+//
+// Only built with the "afero" build tag, so the default build carries no
+// afero dependency:
+//
+//	go build -tags afero ./...
+type AferoFs struct {
+	Fs afero.Fs
+}
+
+// NewAferoFs wraps fs so it satisfies this package's Fs interface.
+func NewAferoFs(fs afero.Fs) AferoFs {
+	return AferoFs{Fs: fs}
+}
+
+func (a AferoFs) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return a.Fs.OpenFile(name, flag, perm)
+}
+
+func (a AferoFs) Create(name string) (File, error) { return a.Fs.Create(name) }
+func (a AferoFs) Open(name string) (File, error)   { return a.Fs.Open(name) }
+
+func (a AferoFs) Rename(oldname, newname string) error  { return a.Fs.Rename(oldname, newname) }
+func (a AferoFs) Remove(name string) error              { return a.Fs.Remove(name) }
+func (a AferoFs) Stat(name string) (os.FileInfo, error) { return a.Fs.Stat(name) }
+func (a AferoFs) Glob(pattern string) ([]string, error) { return afero.Glob(a.Fs, pattern) }
+
+func (a AferoFs) MkdirAll(path string, perm os.FileMode) error { return a.Fs.MkdirAll(path, perm) }
@@ -0,0 +1,69 @@
+package choronolog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoggerWithMemFs(t *testing.T) {
+	fs := NewMemFs()
+	logPath := filepath.Join("/logs", "app.log")
+
+	log, err := New(Config{FilePath: logPath, MaxSize: 1024, Fs: fs, MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	log.Info("hello from memfs")
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	f, err := fs.Open(logPath)
+	if err != nil {
+		t.Fatalf("fs.Open() error: %v", err)
+	}
+	defer f.Close()
+
+	data := make([]byte, 1024)
+	n, err := f.Read(data)
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if n == 0 {
+		t.Fatal("expected data to be written to the in-memory file")
+	}
+
+	if err := log.Rotate(); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+
+	if _, err := fs.Stat(logPath); err != nil {
+		t.Fatalf("expected a fresh log file after rotation, Stat() error: %v", err)
+	}
+
+	backups, err := fs.Glob(filepath.Join("/logs", "app-*.log*"))
+	if err != nil {
+		t.Fatalf("Glob() error: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got %d: %v", len(backups), backups)
+	}
+}
+
+func TestMemFsRemoveAndOpenMissing(t *testing.T) {
+	fs := NewMemFs()
+
+	if _, err := fs.Create("/a.txt"); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if err := fs.Remove("/a.txt"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+
+	if _, err := fs.Open("/a.txt"); !os.IsNotExist(err) {
+		t.Fatalf("Open() error = %v, want os.IsNotExist", err)
+	}
+}
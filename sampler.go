@@ -0,0 +1,101 @@
+package choronolog
+
+import (
+	"sync"
+	"time"
+)
+
+// Sampler decides whether an entry at level should be logged. It is
+// consulted inside write, before the entry is formatted, so a dropped entry
+// costs nothing beyond the Sample call itself. Dropped entries are counted
+// per level and surfaced via Logger.Stats and the synthetic WARNING entry
+// emitted on rotation.
+type Sampler interface {
+	Sample(level LogLevel) bool
+}
+
+// RateLimiter is a Sampler that admits up to ratePerSec entries per second
+// per level, via a token bucket with capacity burst (both set through
+// NewRateLimiter). Once a level's bucket
+// is empty, further entries at that level are dropped until it refills.
+type RateLimiter struct {
+	ratePerSec float64
+	burst      float64
+
+	mu      sync.Mutex
+	buckets [LevelFatal + 1]tokenBucket
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+// NewRateLimiter returns a RateLimiter admitting up to ratePerSec entries per
+// second per level, with burst capacity burst.
+func NewRateLimiter(ratePerSec float64, burst int) *RateLimiter {
+	return &RateLimiter{ratePerSec: ratePerSec, burst: float64(burst)}
+}
+
+func (r *RateLimiter) Sample(level LogLevel) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := &r.buckets[level]
+	now := time.Now()
+	if b.lastFill.IsZero() {
+		b.tokens = r.burst
+	} else {
+		b.tokens = min(r.burst, b.tokens+now.Sub(b.lastFill).Seconds()*r.ratePerSec)
+	}
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// TailSampler is a Sampler that logs the first `first` entries per level in
+// each window, then every `thereafter`th entry after that, mirroring zap's
+// zapcore.NewSamplerWithOptions. If tick is zero, counts never reset and the
+// first/thereafter behavior applies for the lifetime of the Sampler;
+// otherwise counts reset at the start of each tick-long window.
+type TailSampler struct {
+	first      int
+	thereafter int
+	tick       time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      [LevelFatal + 1]int
+}
+
+// NewTailSampler returns a TailSampler that logs the first `first` entries
+// per level, then every `thereafter`th entry, resetting counts every tick
+// (or never, if tick is zero). thereafter is clamped to 1 (log everything
+// past `first`) since 0 would divide by zero in Sample.
+func NewTailSampler(first, thereafter int, tick time.Duration) *TailSampler {
+	if thereafter < 1 {
+		thereafter = 1
+	}
+	return &TailSampler{first: first, thereafter: thereafter, tick: tick, windowStart: time.Now()}
+}
+
+func (s *TailSampler) Sample(level LogLevel) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tick > 0 && time.Since(s.windowStart) >= s.tick {
+		s.counts = [LevelFatal + 1]int{}
+		s.windowStart = time.Now()
+	}
+
+	s.counts[level]++
+	n := s.counts[level]
+	if n <= s.first {
+		return true
+	}
+	return (n-s.first)%s.thereafter == 0
+}
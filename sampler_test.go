@@ -0,0 +1,120 @@
+package choronolog
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSample(t *testing.T) {
+	r := NewRateLimiter(1000, 2)
+
+	if !r.Sample(LevelInfo) {
+		t.Fatal("expected first entry to be admitted")
+	}
+	if !r.Sample(LevelInfo) {
+		t.Fatal("expected second entry (within burst) to be admitted")
+	}
+	if r.Sample(LevelInfo) {
+		t.Fatal("expected third entry to be dropped once burst is exhausted")
+	}
+
+	// A different level has its own bucket.
+	if !r.Sample(LevelError) {
+		t.Fatal("expected a different level to have an independent bucket")
+	}
+
+	time.Sleep(5 * time.Millisecond) // refill at 1000/sec should free up a token
+	if !r.Sample(LevelInfo) {
+		t.Fatal("expected a token to have refilled")
+	}
+}
+
+func TestTailSamplerFirstThenEveryNth(t *testing.T) {
+	s := NewTailSampler(2, 3, 0)
+
+	got := make([]bool, 8)
+	for i := range got {
+		got[i] = s.Sample(LevelInfo)
+	}
+
+	want := []bool{true, true, false, false, true, false, false, true}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Sample() call %d = %v, want %v (full: %v)", i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestTailSamplerZeroThereafterDoesNotPanic(t *testing.T) {
+	s := NewTailSampler(1, 0, 0)
+
+	for i := 0; i < 5; i++ {
+		s.Sample(LevelInfo) // previously panicked with "integer divide by zero" past the first entry
+	}
+}
+
+func TestTailSamplerResetsOnTick(t *testing.T) {
+	s := NewTailSampler(1, 100, 10*time.Millisecond)
+
+	if !s.Sample(LevelInfo) {
+		t.Fatal("expected first entry in a window to be admitted")
+	}
+	if s.Sample(LevelInfo) {
+		t.Fatal("expected second entry in the same window to be dropped")
+	}
+
+	time.Sleep(15 * time.Millisecond)
+
+	if !s.Sample(LevelInfo) {
+		t.Fatal("expected the first entry in a new window to be admitted")
+	}
+}
+
+func TestLoggerSamplerDropsAreCountedAndSummarized(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	log, err := New(Config{
+		FilePath: tmpFile.Name(),
+		MaxSize:  1024,
+		Sampler:  NewTailSampler(1, 1000, 0),
+	})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer log.Close()
+
+	log.Info("first")
+	log.Info("dropped")
+	log.Info("also dropped")
+
+	if got := log.Stats().Dropped[LevelInfo]; got != 2 {
+		t.Fatalf("Stats().Dropped[LevelInfo] = %d, want 2", got)
+	}
+
+	if err := log.Rotate(); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+
+	if got := log.Stats().Dropped[LevelInfo]; got != 0 {
+		t.Fatalf("Stats().Dropped[LevelInfo] after rotation = %d, want 0 (reset)", got)
+	}
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	data, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "sampler dropped 2 entries") {
+		t.Fatalf("expected a drop-summary entry in the rotated file, got %q", data)
+	}
+}